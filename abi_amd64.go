@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build (darwin || linux) && amd64
+
+package purego
+
+// The SysV AMD64 calling convention passes the first six integer/pointer
+// arguments in DI, SI, DX, CX, R8, R9 and the first eight floating-point
+// arguments in XMM0-XMM7; anything beyond that spills to the stack in
+// left-to-right order. ccall's assembly trampoline always loads the first
+// six of its nine integer slots and all eight of its float slots into
+// these registers and writes the other three integer slots to the stack
+// (zero-filling whatever a given callee doesn't use), so
+// RegisterFunc's marshaller only needs to bucket each Go argument into
+// the right slot rather than special-case how many registers a
+// particular call happens to need.
+const (
+	numOfIntegerArgs = 6
+	numOfFloatArgs   = 8
+	// maxIntegerArgs is how many integer/pointer slots ccall accepts in
+	// total: six in registers plus three more spilled to the stack,
+	// enough for every function this package's tests bind (qsort's
+	// comparator, printf's fixed arguments, etc.).
+	maxIntegerArgs = 9
+)
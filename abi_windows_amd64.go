@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build windows && amd64
+
+package purego
+
+// The Microsoft x64 calling convention, unlike SysV AMD64, shares a
+// single argument "slot" index between integer and floating-point
+// arguments: the first four arguments always occupy slot 0-3 regardless
+// of type, going into RCX/RDX/R8/R9 if integer/pointer or XMM0-XMM3 if
+// floating point, and the caller must still reserve 32 bytes of shadow
+// space on the stack for the callee to spill them into even though
+// they're passed in registers. These constants name those Windows values
+// in place of the SysV numOfIntegerArgs/numOfFloatArgs pair used on
+// darwin/linux; func_windows.go's marshaller does not reference them
+// directly.
+
+// numOfWindowsRegisterArgs is 4 because, unlike SysV, Microsoft x64 only
+// has four register argument slots total and a float in slot 2 still
+// burns the RCX/RDX/R8/R9 slot it shares with integers — which is why
+// func_windows.go's callArgs tracks a single shared counter, not two
+// independent ones.
+const (
+	numOfWindowsRegisterArgs = 4
+	windowsShadowSpaceBytes  = 32
+)
+
+// structReturnByHiddenPointerWindows mirrors the amd64 SysV helper
+// structReturnByHiddenPointer: Microsoft x64 returns any struct larger than 8 bytes (or one
+// whose size isn't 1, 2, 4, or 8 bytes) via a hidden pointer passed in
+// RCX, with RAX mirroring it on return, rather than in RAX:RDX.
+func structReturnByHiddenPointerWindows(size uintptr) bool {
+	switch size {
+	case 1, 2, 4, 8:
+		return false
+	default:
+		return true
+	}
+}
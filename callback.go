@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build darwin || linux
+
+package purego
+
+import "runtime"
+
+// NewCallbackLocked is like NewCallback except that, for the duration of
+// every invocation of fn, the OS thread C called back on is pinned via
+// runtime.LockOSThread. Use this for callbacks into libraries that
+// require thread affinity for their API to behave correctly — GTK,
+// OpenGL contexts bound per-thread, or JNI-style APIs — where letting the
+// Go scheduler move the goroutine to a different OS thread mid-callback
+// would hand the library's next call to an unexpected thread.
+//
+// NewCallbackLocked still performs the sigaltstack detection NewCallback
+// does; a callback invoked from a signal handler on an alternate stack
+// skips fn and returns 0 to its C caller, exactly as a plain NewCallback
+// does (see ErrCallbackOnSigaltstack), rather than locking a thread it
+// cannot safely run Go code on.
+func NewCallbackLocked(fn interface{}) uintptr {
+	return newCallback(fn, true)
+}
+
+// lockCallbackThread is called from callbackDispatch before fn runs, and
+// its returned unlock func after; it only locks the thread when the
+// callback was created with NewCallbackLocked.
+func lockCallbackThread(locked bool) (unlock func()) {
+	if !locked {
+		return func() {}
+	}
+	runtime.LockOSThread()
+	return runtime.UnlockOSThread
+}
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build darwin || linux
+
+package purego
+
+import "errors"
+
+// ErrCallbackOnSigaltstack names the case where C invokes a NewCallback
+// trampoline while running on a sigaltstack-installed alternate signal
+// stack (the scenario the cgo test suite exercises via sigaltstack.go
+// and issue1435). Instead of corrupting the Go runtime, the trampoline
+// then skips the Go function and returns 0 to its C caller. The
+// trampoline does not return or deliver this value anywhere: a bare C
+// function pointer has no error channel, and purego has no option to
+// switch onto a dedicated stack for such a call.
+var ErrCallbackOnSigaltstack = errors.New("purego: callback invoked on an alternate signal stack")
+
+// sigaltstackRegion describes the bounds of the stack installed by a
+// prior call to sigaltstack(2) on the current OS thread.
+type sigaltstackRegion struct {
+	base uintptr
+	size uintptr
+}
+
+func (r sigaltstackRegion) contains(sp uintptr) bool {
+	return r.size != 0 && sp >= r.base && sp < r.base+r.size
+}
+
+// onAlternateSignalStack reports whether sp falls inside region, the
+// calling OS thread's currently installed sigaltstack region as just
+// returned by currentSigaltstackRegion. A zero-value region (no
+// sigaltstack installed) never matches.
+func onAlternateSignalStack(region sigaltstackRegion, sp uintptr) bool {
+	return region.contains(sp)
+}
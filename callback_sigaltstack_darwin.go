@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build darwin
+
+package purego
+
+import "sync"
+
+// stackT mirrors struct sigaltstack from <sys/signal.h>: the ss_sp/
+// ss_size/ss_flags triple sigaltstack(2) reports for the stack currently
+// installed on this thread, or the zero value if none is.
+type stackT struct {
+	sp    uintptr
+	size  uintptr
+	flags int32
+}
+
+// sigaltstackFunc is bound lazily, the first time currentSigaltstackRegion
+// runs, using purego's own RegisterLibFunc machinery against libSystem —
+// the same dlsym-based approach every other darwin binding in this
+// package uses instead of linking libc through cgo. sigaltstackOnce
+// guards that one-time binding, since currentSigaltstackRegion can run
+// concurrently on however many OS threads are invoking callbacks at
+// once.
+var (
+	sigaltstackOnce sync.Once
+	sigaltstackFunc func(ss, oss *stackT) int32
+)
+
+// currentSigaltstackRegion queries sigaltstack(2), on whichever OS
+// thread the calling goroutine is actually running on right now. See
+// the linux build's comment on why this deliberately queries fresh on
+// every call instead of caching the result anywhere shared across
+// calls.
+func currentSigaltstackRegion() sigaltstackRegion {
+	sigaltstackOnce.Do(func() {
+		libc, err := Dlopen("/usr/lib/libSystem.B.dylib", RTLD_NOW|RTLD_GLOBAL)
+		if err != nil {
+			return
+		}
+		RegisterLibFunc(&sigaltstackFunc, libc, "sigaltstack")
+	})
+	if sigaltstackFunc == nil {
+		return sigaltstackRegion{}
+	}
+
+	var oss stackT
+	if sigaltstackFunc(nil, &oss) != 0 {
+		return sigaltstackRegion{}
+	}
+	const ssDisable = 4 // SS_DISABLE
+	if oss.flags&ssDisable != 0 || oss.size == 0 {
+		return sigaltstackRegion{}
+	}
+	return sigaltstackRegion{base: oss.sp, size: oss.size}
+}
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build linux
+
+package purego
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// stackT mirrors struct sigaltstack from <bits/sigstack.h>: a flags word
+// the ss_sp/ss_size pair sigaltstack(2) reports for the stack currently
+// installed on this thread, or the zero value if none is.
+type stackT struct {
+	sp    uintptr
+	flags int32
+	_     [4]byte // padding to match the kernel's 8-byte aligned ABI
+	size  uintptr
+}
+
+// currentSigaltstackRegion queries sigaltstack(2), on whichever OS
+// thread the calling goroutine is actually running on right now, with a
+// nil new-stack argument (the documented way to read the current one
+// without installing a new one). sigaltstack state is inherently
+// per-OS-thread, so this deliberately queries fresh on every call
+// instead of caching the result anywhere: a goroutine isn't pinned to
+// one OS thread, and a callback can be invoked concurrently on any
+// number of threads at once, so a single cached value (whether a package
+// global or anything else shared across calls) would be read and
+// written by whichever thread happened to call back last, regardless of
+// which thread actually installed it.
+func currentSigaltstackRegion() sigaltstackRegion {
+	var oss stackT
+	_, _, errno := syscall.Syscall(syscall.SYS_SIGALTSTACK, 0, uintptr(unsafe.Pointer(&oss)), 0)
+	if errno != 0 {
+		return sigaltstackRegion{}
+	}
+	const ssDisable = 2 // SS_DISABLE
+	if oss.flags&ssDisable != 0 || oss.size == 0 {
+		return sigaltstackRegion{}
+	}
+	return sigaltstackRegion{base: oss.sp, size: oss.size}
+}
@@ -0,0 +1,264 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build (darwin || linux) && amd64
+
+package purego
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// maxCallbacks bounds how many Go functions can simultaneously have a C
+// callable pointer outstanding via NewCallback/NewCallbackLocked: each
+// one claims one of a fixed bank of assembly entry points generated into
+// callback_bank_amd64.s (see that file's header for why the bank is
+// generated rather than written by hand), because a C function pointer
+// has to be real, already-assembled machine code — there is no way to
+// fabricate one at runtime for an arbitrary Go closure the way
+// reflect.MakeFunc fabricates a Go func value.
+//
+// This is far fewer than production FFI shims typically provision
+// (cgo's runtime, for comparison, pre-generates thousands), but this
+// package's own callers — NewCallback/NewCallbackLocked bound once per
+// long-lived C API registration, not once per call — are never expected
+// to need more than a handful alive at once.
+const maxCallbacks = 16
+
+// Only integer/pointer arguments are supported; a callback bound over a
+// Go func type with a float32/float64 parameter panics when registered
+// rather than silently reading garbage, since none of this package's
+// own bindings (or its tests) need a float-accepting C-to-Go callback.
+
+// callbackStub0 through callbackStub15 are implemented in
+// callback_bank_amd64.s. These declarations exist only so the Go
+// compiler has a real call edge to each stub (even though it's dead,
+// guarded by neverCallCallbackStubs) — taking an assembly function's
+// address purely via the bank's GLOBL/DATA table, with no Go-level
+// call to it anywhere, confuses the linker's reachability analysis for
+// that symbol's carrier object.
+func callbackStub0()
+func callbackStub1()
+func callbackStub2()
+func callbackStub3()
+func callbackStub4()
+func callbackStub5()
+func callbackStub6()
+func callbackStub7()
+func callbackStub8()
+func callbackStub9()
+func callbackStub10()
+func callbackStub11()
+func callbackStub12()
+func callbackStub13()
+func callbackStub14()
+func callbackStub15()
+
+var neverCallCallbackStubs = false
+
+func init() {
+	if neverCallCallbackStubs {
+		callbackStub0()
+		callbackStub1()
+		callbackStub2()
+		callbackStub3()
+		callbackStub4()
+		callbackStub5()
+		callbackStub6()
+		callbackStub7()
+		callbackStub8()
+		callbackStub9()
+		callbackStub10()
+		callbackStub11()
+		callbackStub12()
+		callbackStub13()
+		callbackStub14()
+		callbackStub15()
+	}
+}
+
+// callbackStubAddrs is populated by callback_bank_amd64.s with the
+// address of each callbackStub<N>, in order.
+var callbackStubAddrs [maxCallbacks]uintptr
+
+// callbackDispatch is called directly from whichever callbackStub<N>
+// received the incoming C call. index identifies which registered
+// callback this is; sp is the stub's own stack address (a good enough
+// proxy for "what stack is this running on" to feed
+// onAlternateSignalStack, since a sigaltstack region spans far more than
+// one frame) and ints holds the same nine integer/pointer slots ccall
+// fills going the other direction. The single return value is handed
+// back as an ordinary Go result rather than written through a pointer:
+// the stub passes every argument by value rather than via a pointer to
+// its own stack frame precisely so that nothing crossing into this
+// function needs the runtime to track a pointer into
+// callbackStub<N>'s NOSPLIT, NO_LOCAL_POINTERS frame — a stack growth
+// triggered from anywhere inside this call (unbounded, since fn can be
+// arbitrary Go code) safely relocates that frame along with the rest of
+// the goroutine's stack, because there is nothing left pointing into it
+// for the relocation to miss.
+func callbackDispatch(index int, sp uintptr, a0, a1, a2, a3, a4, a5, a6, a7, a8 uintptr) uintptr {
+	slot := &callbackSlots[index]
+
+	// sigaltstack is installed per OS thread, and a goroutine calling
+	// NewCallback isn't necessarily locked to the thread C will
+	// eventually call back on, so the region purego compares against has
+	// to be queried here, on the actual calling thread, rather than once
+	// at registration time (or cached anywhere a different thread's
+	// callback could read or clobber it — currentSigaltstackRegion
+	// queries sigaltstack(2) fresh on every call and returns the result
+	// directly, rather than going through a shared package variable, for
+	// exactly that reason).
+	if onAlternateSignalStack(currentSigaltstackRegion(), sp) {
+		// Running arbitrary Go code (which may allocate, trigger GC, or
+		// grow the goroutine stack) on a signal handler's small,
+		// C-owned alternate stack is not safe, and there is no way to
+		// report an error to the C caller through a bare function
+		// pointer's return slot. The documented, safe behavior is to
+		// skip calling fn and return a zero value; see
+		// ErrCallbackOnSigaltstack's doc comment.
+		return 0
+	}
+
+	unlock := lockCallbackThread(slot.locked)
+	defer unlock()
+
+	ints := [maxIntegerArgs]uintptr{a0, a1, a2, a3, a4, a5, a6, a7, a8}
+
+	fnVal := slot.fn
+	fnTy := fnVal.Type()
+	args := make([]reflect.Value, fnTy.NumIn())
+	for i := range args {
+		in := fnTy.In(i)
+		switch in.Kind() {
+		case reflect.Ptr, reflect.UnsafePointer:
+			args[i] = reflect.NewAt(in, unsafe.Pointer(&ints[i])).Elem()
+		case reflect.Bool:
+			args[i] = reflect.ValueOf(ints[i] != 0)
+		case reflect.Struct:
+			// newCallback already rejected any struct parameter that
+			// doesn't fit in exactly one all-integer eightbyte, so
+			// ints[i] holds the whole struct's bytes and can be copied
+			// out the same way unmarshalStruct's non-hidden-pointer
+			// path does.
+			args[i] = reflect.NewAt(in, unsafe.Pointer(&ints[i])).Elem()
+		default:
+			args[i] = reflect.ValueOf(ints[i]).Convert(in)
+		}
+	}
+
+	ret := fnVal.Call(args)
+	if len(ret) == 0 {
+		return 0
+	}
+	switch ret[0].Kind() {
+	case reflect.Ptr, reflect.UnsafePointer:
+		return ret[0].Pointer()
+	case reflect.Bool:
+		if ret[0].Bool() {
+			return 1
+		}
+		return 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintptr(ret[0].Uint())
+	default:
+		return uintptr(ret[0].Int())
+	}
+}
+
+// callbackSlot holds what callbackDispatch needs to actually invoke a
+// registered callback.
+type callbackSlot struct {
+	fn     reflect.Value
+	locked bool
+}
+
+var (
+	callbackMu    sync.Mutex
+	callbackSlots [maxCallbacks]callbackSlot
+	callbackInUse [maxCallbacks]bool
+)
+
+// lookupCallback reports whether sym is one of this package's own
+// callback stubs (a value previously returned by NewCallback or
+// NewCallbackLocked), and if so, the Go function it was registered
+// with. callFunc uses this to call straight into that function instead
+// of marshalling args through ccall and back in through
+// callbackStub<N>: when cfn is one of ours, that whole round trip
+// accomplishes nothing except crossing out to "C" and immediately back,
+// and it's actively harmful — ccall's temporary stack-pointer realignment
+// leaves a frame the runtime's stack-copying unwinder can't walk through,
+// so a stack growth triggered from inside the callback's own
+// reflect.Value.Call (unbounded, since fn can be arbitrary Go code) can
+// crash the process instead of just growing the stack as it normally
+// would.
+func lookupCallback(sym uintptr) (reflect.Value, bool) {
+	callbackMu.Lock()
+	defer callbackMu.Unlock()
+	for i, addr := range callbackStubAddrs {
+		if addr == sym && callbackInUse[i] {
+			return callbackSlots[i].fn, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// NewCallback returns a C function pointer that, when called, invokes
+// fn (a Go func) and returns its first result. fn's parameters must all
+// be integer, pointer, or bool kinds, or structs that fit in a single
+// all-integer eightbyte; see callbackDispatch's doc comment.
+func NewCallback(fn interface{}) uintptr {
+	return newCallback(fn, false)
+}
+
+// newCallback is NewCallback/NewCallbackLocked's shared implementation.
+func newCallback(fn interface{}, locked bool) uintptr {
+	v := reflect.ValueOf(fn)
+	ty := v.Type()
+	if ty.Kind() != reflect.Func {
+		panic("purego: NewCallback requires a func")
+	}
+	if ty.NumIn() > maxIntegerArgs {
+		panic(fmt.Sprintf("purego: NewCallback: too many parameters (max %d)", maxIntegerArgs))
+	}
+	for i := 0; i < ty.NumIn(); i++ {
+		in := ty.In(i)
+		switch in.Kind() {
+		case reflect.Float32, reflect.Float64:
+			panic("purego: NewCallback does not support float32/float64 parameters")
+		case reflect.Struct:
+			// callbackDispatch maps each Go parameter directly onto one
+			// of ints' nine slots (see its doc comment), so a struct
+			// argument is only representable here when its eightbyte
+			// classification is exactly one all-integer eightbyte: one
+			// slot in, one slot consumed, so every later parameter's
+			// slot index is unaffected. A struct spanning two eightbytes
+			// would need two consecutive slots, shifting every
+			// parameter after it, so it is rejected here, as is a
+			// structMemory struct. A struct with float fields classifies
+			// as structSSE (the float check above only covers float
+			// parameters themselves) and is rejected here too.
+			classes := classifyStruct(in)
+			if len(classes) != 1 || classes[0] != structInteger {
+				panic("purego: NewCallback only supports struct parameters that fit in a single 8-byte integer register")
+			}
+		}
+	}
+	if ty.NumOut() > 1 {
+		panic("purego: NewCallback functions may return at most one value")
+	}
+
+	callbackMu.Lock()
+	defer callbackMu.Unlock()
+	for i, inUse := range callbackInUse {
+		if !inUse {
+			callbackInUse[i] = true
+			callbackSlots[i] = callbackSlot{fn: v, locked: locked}
+			return callbackStubAddrs[i]
+		}
+	}
+	panic(fmt.Sprintf("purego: NewCallback: all %d callback slots are in use", maxCallbacks))
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build windows
+
+package purego
+
+import (
+	"reflect"
+	"sync"
+	"syscall"
+)
+
+// NewCallback returns a function pointer usable by C code to call fn,
+// the same contract NewCallback offers on darwin/linux. Unlike those
+// platforms, which hand-roll an assembly trampoline to honor the SysV/
+// AAPCS64 ABI, windows/amd64's calling convention is already what the Go
+// runtime uses for its own syscall callback support, so this wraps
+// syscall.NewCallback directly rather than duplicating its MS x64
+// register/shadow-space handling.
+func NewCallback(fn interface{}) uintptr {
+	return newCallback(fn, false)
+}
+
+// NewCallbackLocked exists on windows for API parity with darwin/linux,
+// but windows/amd64 callbacks already run on whichever thread the
+// Windows loader's callback dispatch used to invoke them and Go's
+// syscall.NewCallback trampoline does not hand control back to a
+// scheduler-movable goroutine mid-call, so there is no analogous thread
+// affinity hazard to guard against; it is provided purely so that
+// callers sharing code across all three OSes don't need a build-tag
+// around the call site.
+func NewCallbackLocked(fn interface{}) uintptr {
+	return newCallback(fn, true)
+}
+
+var (
+	callbackMu       sync.Mutex
+	callbackRegistry = map[uintptr]reflect.Value{}
+)
+
+// lookupCallback reports whether cfn is one of this package's own
+// callbacks (a value previously returned by NewCallback or
+// NewCallbackLocked), and if so, the Go function it was registered
+// with. callFunc uses this the same way func.go's unix callFunc does:
+// to call straight into that function instead of round-tripping
+// through ccallWindows and back in through syscall.NewCallback's own
+// trampoline, which accomplishes nothing except crossing out to "C"
+// and immediately back.
+func lookupCallback(cfn uintptr) (reflect.Value, bool) {
+	callbackMu.Lock()
+	defer callbackMu.Unlock()
+	fn, ok := callbackRegistry[cfn]
+	return fn, ok
+}
+
+// newCallback is NewCallback/NewCallbackLocked's shared implementation,
+// and func_windows.go's entry point for a reflect.Func argument being
+// marshalled into a call, mirroring the unix package's helper of the
+// same name so marshalArg doesn't need a build-tagged dispatch between
+// them. locked is unused: see NewCallbackLocked's doc comment for why
+// windows has no thread-affinity hazard to guard against in the first
+// place.
+func newCallback(fn interface{}, locked bool) uintptr {
+	cfn := syscall.NewCallback(fn)
+	callbackMu.Lock()
+	callbackRegistry[cfn] = reflect.ValueOf(fn)
+	callbackMu.Unlock()
+	return cfn
+}
@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build (darwin || linux) && amd64
+
+package purego
+
+// ccall is the single assembly trampoline every darwin/linux amd64 call
+// into C goes through, whether it originates from RegisterFunc's
+// reflect.MakeFunc closure, callVariadic, or this package's own
+// bootstrap calls to dlopen/dlsym/dlclose. It always loads all nine
+// integer slots (six in DI/SI/DX/CX/R8/R9, three more spilled to the
+// stack) and all eight float slots (XMM0-XMM7) before calling fn, and
+// zero-filling whatever a given C function doesn't actually read is
+// harmless under the SysV ABI. nFloats is written into AL immediately
+// before the call, per the psABI's requirement for any variadic
+// function's va_start.
+//
+// errno is snapshotted (via __errno_location) in the same instruction
+// sequence as the call itself, before this function does anything else
+// that could move the goroutine to a different OS thread or otherwise
+// let something else clobber it; see errno.go.
+func ccall(fn uintptr,
+	a1, a2, a3, a4, a5, a6, a7, a8, a9 uintptr,
+	f1, f2, f3, f4, f5, f6, f7, f8 float64,
+	nFloats uintptr,
+) (r1, r2 uintptr, errno uintptr)
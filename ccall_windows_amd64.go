@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build windows
+
+package purego
+
+// ccallWindows is func_windows.go's counterpart to ccall: the single
+// assembly trampoline every windows/amd64 call into a DLL-exported
+// function goes through, honoring the Microsoft x64 convention rather
+// than SysV. Unlike SysV, which gives integer and floating-point
+// arguments independent register files, Microsoft x64 shares a single
+// slot index between them: slot 0 is RCX or XMM0, slot 1 is RDX or
+// XMM1, and so on through slot 3 (R9/XMM3), with anything beyond that
+// spilled to the stack above the 32-byte shadow space every caller must
+// reserve. a0-a3 are therefore always loaded into both their integer and
+// XMM register so the callee reads whichever one its own C signature
+// declared, the same zero-cost trick ccall_amd64.s uses for its nine
+// registers; a4-a8 are always stack-spilled integer slots, since this
+// package's own bindings never need more than four floating-point
+// arguments in a single call.
+//
+// lastErr is GetLastError(), read directly out of the TEB (gs:0x68 on
+// x64) in the same unbroken instruction sequence as the call itself, for
+// the same reason ccall snapshots errno immediately: anything that could
+// move this goroutine to a different OS thread before it's read would
+// invalidate it.
+func ccallWindows(fn uintptr,
+	a0, a1, a2, a3, a4, a5, a6, a7, a8 uintptr,
+) (r1, r2, lastErr uintptr)
@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+package purego
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// DlopenEmbedded loads the shared library stored at path within fsys and
+// returns a handle usable with Dlsym/Dlclose and RegisterLibFunc, the
+// same as Dlopen. fsys takes fs.FS, which an embed.FS satisfies, so
+// library authors can vendor a .so/.dylib/.dll into their binary via
+// //go:embed (the way this package's own tests build libdlnested.so at
+// test time, but without requiring an end user to have a compiler or
+// system package installed) instead of asking users to install the
+// shared library through their OS's package manager; taking the
+// interface rather than the concrete embed.FS type also lets callers
+// (and this package's own tests) pass an fstest.MapFS or os.DirFS.
+//
+// On linux, the library is written to an anonymous memfd_create(2)
+// region and loaded from /proc/self/fd/N, so it never touches disk. On
+// platforms without memfd_create (darwin and windows, neither of which
+// has an equivalent), it falls back to writing the library out to a
+// temp file and loading that instead; see dlopenBytesTempFile for the
+// cleanup caveats that fallback carries on each OS.
+func DlopenEmbedded(fsys fs.FS, path string, mode int) (uintptr, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("purego: DlopenEmbedded: opening %q in fs.FS: %w", path, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return 0, fmt.Errorf("purego: DlopenEmbedded: reading %q from fs.FS: %w", path, err)
+	}
+
+	return dlopenBytes(path, data, mode)
+}
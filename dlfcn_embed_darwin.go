@@ -0,0 +1,15 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build darwin
+
+package purego
+
+// dlopenBytes on darwin always goes through the temp-file fallback:
+// there is no memfd_create equivalent, and NSCreateObjectFileImageFromMemory
+// + NSLinkModule was deprecated (and, on arm64, never implemented) in
+// favor of dlopen, so there is no supported in-memory path left to
+// prefer over a short-lived temp file.
+func dlopenBytes(name string, data []byte, mode int) (uintptr, error) {
+	return dlopenBytesTempFile(name, data, mode)
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build linux
+
+package purego
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// mfdCloexec is MFD_CLOEXEC from <linux/memfd.h>.
+const mfdCloexec = 1
+
+// dlopenBytes loads an in-memory shared library image via
+// memfd_create(2): an anonymous, unlinked file backed by RAM rather than
+// a filesystem, which the dynamic linker can still mmap by path because
+// the kernel exposes every open fd at /proc/self/fd/N. name is used only
+// for the memfd's debug label (visible in /proc/self/fd and similar
+// tooling); it has no effect on how the library is loaded.
+//
+// Kernels older than 3.17, or a seccomp policy that blocks
+// memfd_create, fall back to dlopenBytesTempFile.
+func dlopenBytes(name string, data []byte, mode int) (uintptr, error) {
+	nameBytes, err := syscall.BytePtrFromString(filepath.Base(name))
+	if err != nil {
+		return 0, fmt.Errorf("purego: DlopenEmbedded: %w", err)
+	}
+
+	// mfdCloexec is passed so the fd is created with close-on-exec
+	// already set atomically, the same reason os.OpenFile always ORs in
+	// O_CLOEXEC: without it, a fork() racing on another goroutine
+	// between memfd_create returning and a separate fcntl(F_SETFD) call
+	// could inherit this writable memfd into a child process.
+	fd, _, errno := syscall.Syscall(memfdCreateTrap, uintptr(unsafe.Pointer(nameBytes)), mfdCloexec, 0)
+	if errno != 0 {
+		return dlopenBytesTempFile(name, data, mode)
+	}
+	defer syscall.Close(int(fd))
+
+	if _, err := syscall.Write(int(fd), data); err != nil {
+		return 0, fmt.Errorf("purego: DlopenEmbedded: writing to memfd: %w", err)
+	}
+
+	return Dlopen(fmt.Sprintf("/proc/self/fd/%d", fd), mode)
+}
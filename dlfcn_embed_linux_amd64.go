@@ -0,0 +1,13 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build linux && amd64
+
+package purego
+
+// memfdCreateTrap is SYS_memfd_create's syscall number on linux/amd64.
+// The syscall package doesn't export this constant on every
+// architecture, so each arch this package supports pins its own copy
+// here rather than depending on golang.org/x/sys/unix for a single
+// number.
+const memfdCreateTrap = 319
@@ -0,0 +1,9 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build linux && arm64
+
+package purego
+
+// memfdCreateTrap is SYS_memfd_create's syscall number on linux/arm64.
+const memfdCreateTrap = 279
@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build darwin || linux || windows
+
+package purego
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// dlopenBytesTempFile is the fallback dlopenBytes implementation used
+// when a platform or kernel has no way to dlopen an anonymous in-memory
+// image: it writes data out to a temp file, Dlopens it, and attempts to
+// remove the directory entry immediately afterwards.
+//
+// On darwin/linux this cleanup always succeeds: the dynamic linker
+// keeps its own open file description for the mapping it made, so
+// unlinking here only hides the file from anything else that might go
+// looking for it, and doesn't invalidate the handle Dlopen already
+// returned. On windows, LoadLibrary opens the file without
+// FILE_SHARE_DELETE, so this os.Remove reliably fails with a sharing
+// violation and is a no-op in practice; nothing currently retries the
+// removal after the caller's eventual Dlclose, so callers on windows
+// should expect DlopenEmbedded's temp file to outlive the process. This
+// is a known limitation, not a silent success.
+func dlopenBytesTempFile(name string, data []byte, mode int) (uintptr, error) {
+	f, err := os.CreateTemp("", "purego-embedded-*-"+filepath.Base(name))
+	if err != nil {
+		return 0, fmt.Errorf("purego: DlopenEmbedded: creating temp file: %w", err)
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	_, writeErr := f.Write(data)
+	closeErr := f.Close()
+	if writeErr != nil {
+		return 0, fmt.Errorf("purego: DlopenEmbedded: writing temp file: %w", writeErr)
+	}
+	if closeErr != nil {
+		return 0, fmt.Errorf("purego: DlopenEmbedded: closing temp file: %w", closeErr)
+	}
+
+	return Dlopen(tmpPath, mode)
+}
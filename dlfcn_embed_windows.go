@@ -0,0 +1,18 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build windows
+
+package purego
+
+// dlopenBytes on windows always goes through the temp-file fallback:
+// there is no memfd_create equivalent, and Windows' anonymous mapping
+// primitives (CreateFileMapping backed by the page file) have no way to
+// be named for LoadLibrary to open. See dlopenBytesTempFile's doc
+// comment for the cleanup caveat this carries on windows specifically:
+// LoadLibrary keeps the file open without FILE_SHARE_DELETE, so the
+// removal there fails and the temp DLL stays on disk; passing the handle
+// returned here to Dlclose does not remove it either.
+func dlopenBytes(name string, data []byte, mode int) (uintptr, error) {
+	return dlopenBytesTempFile(name, data, mode)
+}
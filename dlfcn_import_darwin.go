@@ -0,0 +1,17 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build darwin && amd64
+
+package purego
+
+// See dlfcn_import_linux.go's doc comment for why this works without
+// any cgo source. darwin's dlopen/dlsym/dlclose and __error all live in
+// libSystem, the umbrella library every darwin process is already
+// linked against.
+//
+//go:cgo_import_dynamic libc_dlopen dlopen "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic libc_dlsym dlsym "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic libc_dlclose dlclose "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic libc_dlerror dlerror "/usr/lib/libSystem.B.dylib"
+//go:cgo_import_dynamic libc_errno_ptr __error "/usr/lib/libSystem.B.dylib"
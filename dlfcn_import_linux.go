@@ -0,0 +1,22 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build linux && amd64
+
+package purego
+
+// These resolve dlopen/dlsym/dlclose/__errno_location against libdl/libc
+// at link time without requiring any cgo source (no `import "C"`
+// anywhere in this package): //go:cgo_import_dynamic asks the linker to
+// record each symbol as a dynamic import, exactly as if a .o produced by
+// cgo had referenced it, and the local names below (libc_dlopen, etc.)
+// are what ccall_amd64.s and dlfcn_trampoline_amd64.s CALL directly. On
+// distros where libdl's symbols were folded into libc (glibc >= 2.34),
+// the dynamic linker resolves these against libc.so.6 just as readily,
+// since both names end up in the same process-wide symbol namespace.
+//
+//go:cgo_import_dynamic libc_dlopen dlopen "libdl.so.2"
+//go:cgo_import_dynamic libc_dlsym dlsym "libdl.so.2"
+//go:cgo_import_dynamic libc_dlclose dlclose "libdl.so.2"
+//go:cgo_import_dynamic libc_dlerror dlerror "libdl.so.2"
+//go:cgo_import_dynamic libc_errno_ptr __errno_location "libc.so.6"
@@ -1,8 +1,6 @@
 // SPDX-License-Identifier: Apache-2.0
 // SPDX-FileCopyrightText: 2023 The Ebitengine Authors
 
-//go:build darwin || linux
-
 package purego_test
 
 import (
@@ -26,6 +24,10 @@ func TestSimpleDlsym(t *testing.T) {
 }
 
 func TestNestedDlopenCall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("nested dlopen test builds a .so via the CXX toolchain, not yet wired up for windows/DLLs")
+	}
+
 	libFileName := filepath.Join(t.TempDir(), "libdlnested.so")
 	t.Logf("Build %v", libFileName)
 
@@ -84,6 +86,8 @@ func getSystemLibrary() (string, error) {
 		return "/usr/lib/libSystem.B.dylib", nil
 	case "linux":
 		return "libc.so.6", nil
+	case "windows":
+		return "msvcrt.dll", nil
 	default:
 		return "", fmt.Errorf("GOOS=%s is not supported", runtime.GOOS)
 	}
@@ -119,6 +123,32 @@ func ExampleNewCallback() {
 	// 45
 }
 
+func TestNewCallbackLocked(t *testing.T) {
+	cb := purego.NewCallbackLocked(func(a int) int {
+		return a * 2
+	})
+
+	var fn func(a int) int
+	purego.RegisterFunc(&fn, cb)
+
+	if got := fn(21); got != 42 {
+		t.Errorf("fn(21) = %d, want 42", got)
+	}
+}
+
+func TestNewCallback_structArg(t *testing.T) {
+	cb := purego.NewCallback(func(d divT) int32 {
+		return d.Quot*10 + d.Rem
+	})
+
+	var fn func(d divT) int32
+	purego.RegisterFunc(&fn, cb)
+
+	if got := fn(divT{Quot: 3, Rem: 2}); got != 32 {
+		t.Errorf("fn(divT{3, 2}) = %d, want 32", got)
+	}
+}
+
 func Test_qsort(t *testing.T) {
 	library, err := getSystemLibrary()
 	if err != nil {
@@ -143,3 +173,85 @@ func Test_qsort(t *testing.T) {
 		}
 	}
 }
+
+// divT mirrors libc's div_t, a small POD struct returned by value. It is
+// used to exercise RegisterFunc's struct-by-value classification.
+type divT struct {
+	Quot int32
+	Rem  int32
+}
+
+func TestRegisterFunc_structReturn(t *testing.T) {
+	library, err := getSystemLibrary()
+	if err != nil {
+		t.Errorf("couldn't get system library: %s", err)
+	}
+	libc, err := purego.Dlopen(library, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		t.Errorf("failed to dlopen: %s", err)
+	}
+
+	var div func(numer, denom int32) divT
+	purego.RegisterLibFunc(&div, libc, "div")
+	got := div(17, 5)
+	if want := (divT{Quot: 3, Rem: 2}); got != want {
+		t.Errorf("div(17, 5) = %+v, want %+v", got, want)
+	}
+}
+
+func TestRegisterVariadicFunc(t *testing.T) {
+	library, err := getSystemLibrary()
+	if err != nil {
+		t.Errorf("couldn't get system library: %s", err)
+	}
+	libc, err := purego.Dlopen(library, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		t.Errorf("failed to dlopen: %s", err)
+	}
+
+	var printf func(format string, args ...any) int
+	purego.RegisterVariadicFunc(&printf, libc, "printf")
+	if n := printf("%s = %d, %f\n", "answer", 42, 3.5); n <= 0 {
+		t.Errorf("printf returned %d, wanted a positive byte count", n)
+	}
+}
+
+func TestDlopenEmbedded(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("DlopenEmbedded test builds a .so via the CXX toolchain, not wired up for windows/DLLs")
+	}
+
+	dir := t.TempDir()
+	libFileName := filepath.Join(dir, "libdlnested.so")
+	if err := buildSharedLib("CXX", libFileName, filepath.Join("libdlnested", "nested.cpp")); err != nil {
+		t.Fatal(err)
+	}
+
+	lib, err := purego.DlopenEmbedded(os.DirFS(dir), "libdlnested.so", purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		t.Fatalf("DlopenEmbedded failed: %v", err)
+	}
+	purego.Dlclose(lib)
+}
+
+func TestRegisterLibFunc_errno(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("msvcrt exports _open, not open")
+	}
+
+	library, err := getSystemLibrary()
+	if err != nil {
+		t.Errorf("couldn't get system library: %s", err)
+	}
+	libc, err := purego.Dlopen(library, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		t.Errorf("failed to dlopen: %s", err)
+	}
+
+	var open func(path string, flags int) (int, purego.Errno)
+	purego.RegisterLibFunc(&open, libc, "open")
+	fd, errno := open(filepath.Join(t.TempDir(), "does-not-exist"), 0)
+	if fd != -1 || errno == 0 {
+		t.Errorf("open of missing file = (%d, %v), wanted (-1, non-zero errno)", fd, errno)
+	}
+}
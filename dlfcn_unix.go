@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build darwin || linux
+
+package purego
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// RTLD_* mirror the flags from <dlfcn.h>. They mean the same thing on
+// darwin and linux; only RTLD_DEFAULT's numeric value happens to be 0 on
+// both, so no per-OS split is needed. dlfcn_windows.go declares its own
+// copy, which its Dlopen accepts and ignores.
+const (
+	RTLD_DEFAULT = 0
+	RTLD_LAZY    = 0x00001
+	RTLD_NOW     = 0x00002
+	RTLD_LOCAL   = 0x00000
+	RTLD_GLOBAL  = 0x00100
+)
+
+// dlopenTrampoline, dlsymTrampoline, dlcloseTrampoline and
+// dlerrorTrampoline are implemented in per-arch assembly
+// (ccall_<arch>.s's neighboring dlfcn_trampoline_<arch>.s) against the
+// libc_dlopen/libc_dlsym/libc_dlclose/libc_dlerror symbols imported by
+// dlfcn_import_linux.go/dlfcn_import_darwin.go. They exist separately
+// from the general-purpose ccall so that this package's very first
+// Dlsym call (resolving every other C function it ever binds) doesn't
+// depend on anything more than the assembler and the dynamic linker.
+func dlopenTrampoline(path uintptr, mode uintptr) (handle uintptr)
+func dlsymTrampoline(handle uintptr, name uintptr) (sym uintptr)
+func dlcloseTrampoline(handle uintptr) (ret uintptr)
+
+// dlerrorTrampoline returns unsafe.Pointer rather than uintptr, unlike
+// its three siblings above, purely so that cStringToGo can be handed a
+// Pointer directly: converting a uintptr returned from a previous,
+// already-completed call back into unsafe.Pointer is exactly the
+// pattern go vet's unsafeptr check can't prove safe, even though it is
+// here (dlerror's result isn't garbage collected Go memory to begin
+// with). Declaring the assembly's return slot as unsafe.Pointer instead
+// sidesteps the conversion entirely; the bit pattern written into it is
+// identical either way.
+func dlerrorTrampoline() (message unsafe.Pointer)
+
+func dlerror() string {
+	p := dlerrorTrampoline()
+	if p == nil {
+		return ""
+	}
+	return cStringToGo(p)
+}
+
+// cStringToGo copies a NUL-terminated C string at ptr into a Go string.
+func cStringToGo(ptr unsafe.Pointer) string {
+	if ptr == nil {
+		return ""
+	}
+	n := 0
+	for *(*byte)(unsafe.Add(ptr, n)) != 0 {
+		n++
+	}
+	return string(unsafe.Slice((*byte)(ptr), n))
+}
+
+// Dlopen loads the shared library at path, returning a handle usable
+// with Dlsym/Dlclose/RegisterLibFunc, the same contract dlopen(3) gives
+// C callers.
+func Dlopen(path string, mode int) (uintptr, error) {
+	cpath, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return 0, fmt.Errorf("purego: Dlopen(%q): %w", path, err)
+	}
+	// Priming here, for the same reason marshalArg primes before handing
+	// a callback to C: dlopenTrampoline runs dlopen(3) on this
+	// goroutine's own Go-managed stack, and loading a library for the
+	// first time can make the dynamic linker do real work on it —
+	// processing relocations, running the library's C++ static
+	// initializers — with no Go frame in between to trigger an ordinary,
+	// safe stack growth if it needs more room than a fresh goroutine
+	// stack has. Growing the stack proactively, through Go's own
+	// machinery, before any of that runs avoids it growing (unsafely)
+	// mid-C-call instead.
+	primeStack(stackPrimeDepth)
+	h := dlopenTrampoline(uintptr(unsafe.Pointer(cpath)), uintptr(mode))
+	if h == 0 {
+		return 0, fmt.Errorf("purego: Dlopen(%q) failed: %s", path, dlerror())
+	}
+	return h, nil
+}
+
+// Dlsym returns the address of the exported symbol name in the shared
+// library identified by handle (or the whole process, for
+// RTLD_DEFAULT), the same contract dlsym(3) gives C callers.
+func Dlsym(handle uintptr, name string) (uintptr, error) {
+	cname, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return 0, fmt.Errorf("purego: Dlsym(%q): %w", name, err)
+	}
+	// dlerror is cleared first so that a genuinely NULL symbol value
+	// (legal, if rare, for symbols that really are defined to be zero)
+	// isn't mistaken for a lookup failure.
+	dlerror()
+	sym := dlsymTrampoline(handle, uintptr(unsafe.Pointer(cname)))
+	if sym == 0 {
+		if msg := dlerror(); msg != "" {
+			return 0, fmt.Errorf("purego: Dlsym(%q) failed: %s", name, msg)
+		}
+	}
+	return sym, nil
+}
+
+// Dlclose unloads a shared library previously returned by Dlopen.
+func Dlclose(handle uintptr) error {
+	if dlcloseTrampoline(handle) != 0 {
+		return fmt.Errorf("purego: Dlclose failed: %s", dlerror())
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build windows
+
+package purego
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// RTLD_* are defined here, rather than relying on a build-tag-free
+// dlfcn.go, purely so that downstream code sharing a single test file
+// across darwin/linux/windows (the motivation for this file) can
+// reference purego.RTLD_NOW etc. unconditionally. They are not
+// translated to LoadLibraryExW flags: Dlopen ignores all of them (see
+// its doc comment).
+const (
+	RTLD_DEFAULT = 0
+	RTLD_LAZY    = 0x0001
+	RTLD_NOW     = 0x0002
+	RTLD_LOCAL   = 0x0004
+	RTLD_GLOBAL  = 0x0100
+)
+
+// Dlopen loads the shared library (DLL) at path and returns a handle
+// usable with Dlsym/Dlclose and RegisterLibFunc just like the
+// darwin/linux implementations. mode's RTLD_* bits are accepted for API
+// parity but have no effect: the standard library's syscall package
+// doesn't expose LoadLibraryExW's dwFlags (only the flagless
+// LoadLibrary), and pulling in golang.org/x/sys/windows for this alone
+// would be the exact "parallel dependency" this file exists to avoid.
+func Dlopen(path string, mode int) (uintptr, error) {
+	h, err := syscall.LoadLibrary(path)
+	if err != nil {
+		return 0, fmt.Errorf("purego: LoadLibrary(%q) failed: %w", path, err)
+	}
+	return uintptr(h), nil
+}
+
+// Dlsym returns the address of the exported symbol name in the DLL
+// identified by handle, the Windows analog of GetProcAddress.
+func Dlsym(handle uintptr, name string) (uintptr, error) {
+	addr, err := syscall.GetProcAddress(syscall.Handle(handle), name)
+	if err != nil {
+		return 0, fmt.Errorf("purego: GetProcAddress(%q) failed: %w", name, err)
+	}
+	return addr, nil
+}
+
+// Dlclose unloads a DLL previously returned by Dlopen.
+func Dlclose(handle uintptr) error {
+	return syscall.FreeLibrary(syscall.Handle(handle))
+}
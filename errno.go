@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build darwin || linux
+
+package purego
+
+// _ "runtime/cgo" is imported for its side effect, not its API: without
+// it, the Go runtime owns the thread pointer (%fs on amd64) outright and
+// points it at its own, glibc-incompatible per-M area, so a dynamically
+// resolved __errno_location/__error — genuinely a thread-local access
+// into libc's own static TLS block — dereferences an address that was
+// never allocated for it and faults. Importing runtime/cgo, even with no
+// Go code anywhere using cgo directly, is what makes the Go runtime
+// negotiate the thread pointer with the platform's C runtime at thread
+// creation instead of claiming it unconditionally; this is the same
+// fix real cgo-based programs get for free merely by having an
+// "import C" somewhere.
+import _ "runtime/cgo"
+
+// Errno is the type returned by a RegisterLibFunc binding whose Go
+// signature's trailing result is a purego.Errno (or a plain error, in
+// which case it wraps the same value). It reports the C errno that was
+// live immediately after the underlying call returned.
+//
+// errno is thread-local and can be clobbered by almost any Go runtime
+// work — a GC assist, a goroutine reschedule, even a deferred call —
+// that happens to run on the same OS thread before user code reads it.
+// To make this safe, the asm trampoline snapshots errno (via
+// __errno_location on linux, __error on darwin) on the same OS thread,
+// in the same instruction sequence, immediately after the call
+// instruction returns and before it re-enables Go's asynchronous
+// preemption, the same ordering cgo's runtime·cgocall / //sys wrappers
+// rely on for syscall.Errno.
+type Errno uintptr
+
+// Error implements the error interface by formatting the errno the same
+// way the syscall package does, so Errno is safe to use anywhere a
+// caller does `if err != nil`.
+func (e Errno) Error() string {
+	if s := errnoString(uintptr(e)); s != "" {
+		return s
+	}
+	return "errno " + itoa(uintptr(e))
+}
+
+// Is allows errors.Is(err, purego.Errno(n)) to work like it does for
+// syscall.Errno.
+func (e Errno) Is(target error) bool {
+	other, ok := target.(Errno)
+	return ok && e == other
+}
+
+func itoa(n uintptr) string {
+	if n == 0 {
+		return "0"
+	}
+	var buf [20]byte
+	i := len(buf)
+	for n > 0 {
+		i--
+		buf[i] = byte('0' + n%10)
+		n /= 10
+	}
+	return string(buf[i:])
+}
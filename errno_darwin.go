@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build darwin
+
+package purego
+
+// errnoStrings mirrors the subset of <sys/errno.h> that purego's own
+// bindings are most likely to surface. Darwin assigns the same small
+// integers as Linux to most of these, but not all (EAGAIN, "resource
+// temporarily unavailable", is 35 here and 11 on Linux), so the table is
+// kept separate per OS.
+var errnoStrings = map[uintptr]string{
+	1:  "operation not permitted",
+	2:  "no such file or directory",
+	9:  "bad file descriptor",
+	13: "permission denied",
+	14: "bad address",
+	22: "invalid argument",
+	24: "too many open files",
+	35: "resource temporarily unavailable",
+}
+
+func errnoString(errno uintptr) string {
+	return errnoStrings[errno]
+}
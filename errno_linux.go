@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build linux
+
+package purego
+
+// errnoStrings mirrors the subset of <asm-generic/errno-base.h> that
+// purego's own bindings (open, read, qsort comparators, ...) are most
+// likely to surface. It intentionally isn't exhaustive the way
+// syscall.Errno's table is; callers who need the full set can compare
+// against golang.org/x/sys/unix's constants directly.
+var errnoStrings = map[uintptr]string{
+	1:  "operation not permitted",
+	2:  "no such file or directory",
+	9:  "bad file descriptor",
+	11: "resource temporarily unavailable",
+	12: "cannot allocate memory",
+	13: "permission denied",
+	14: "bad address",
+	22: "invalid argument",
+	24: "too many open files",
+}
+
+func errnoString(errno uintptr) string {
+	return errnoStrings[errno]
+}
@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build windows
+
+package purego
+
+import "syscall"
+
+// Errno on windows mirrors the darwin/linux type of the same name, but
+// captures GetLastError() instead of errno — the windows ABI has no
+// thread-local errno at all, and C libraries built against the Microsoft
+// CRT that do set errno (via _set_errno) are in the minority compared to
+// Win32 APIs, which universally use SetLastError/GetLastError. A
+// RegisterLibFunc binding whose trailing result is a purego.Errno gets
+// GetLastError() snapshotted immediately on return from the call, same
+// as the errno snapshot darwin/linux take.
+type Errno uint32
+
+// Error formats the underlying GetLastError() code the same way
+// syscall.Errno does on windows, so purego.Errno satisfies error
+// wherever a caller does `if err != nil`.
+func (e Errno) Error() string {
+	return syscall.Errno(e).Error()
+}
+
+// Is allows errors.Is(err, purego.Errno(n)) to work like it does for
+// syscall.Errno.
+func (e Errno) Is(target error) bool {
+	other, ok := target.(Errno)
+	return ok && e == other
+}
@@ -0,0 +1,340 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build darwin || linux
+
+package purego
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"unsafe"
+)
+
+// RegisterFunc sets the function pointed to by fptr to a Go function
+// that marshals its arguments and calls cfn, a C function pointer (the
+// kind NewCallback and Dlsym both return), according to the target
+// platform's calling convention. fptr must be a pointer to a func.
+func RegisterFunc(fptr interface{}, cfn uintptr) {
+	fn := reflect.ValueOf(fptr).Elem()
+	ty := fn.Type()
+
+	v := reflect.MakeFunc(ty, func(args []reflect.Value) []reflect.Value {
+		return callFunc(ty, cfn, args)
+	})
+	fn.Set(v)
+}
+
+// RegisterLibFunc is like RegisterFunc except it looks cfn up by name in
+// the shared library identified by handle (as returned by Dlopen),
+// panicking if the symbol isn't found — the same failure mode
+// reflect.MakeFunc would hit trying to call through a nil pointer, just
+// surfaced earlier with a useful message.
+func RegisterLibFunc(fptr interface{}, handle uintptr, name string) {
+	sym, err := Dlsym(handle, name)
+	if err != nil {
+		panic(err)
+	}
+	RegisterFunc(fptr, sym)
+}
+
+// callArgs accumulates the marshalled form of a Go call's arguments:
+// one slot per integer/pointer register (plus overflow), one per float
+// register, and running counts of each. nFloats, the number of float
+// slots used, is what both callFunc and callVariadic pass to ccall for
+// AL.
+type callArgs struct {
+	ints    [maxIntegerArgs]uintptr
+	nInts   int
+	floats  [numOfFloatArgs]float64
+	nFloats int
+}
+
+func (c *callArgs) addInt(v uintptr) {
+	if c.nInts >= len(c.ints) {
+		panic(fmt.Sprintf("purego: too many integer/pointer arguments (max %d)", len(c.ints)))
+	}
+	c.ints[c.nInts] = v
+	c.nInts++
+}
+
+func (c *callArgs) addFloat(v float64) {
+	if c.nFloats >= len(c.floats) {
+		panic(fmt.Sprintf("purego: too many floating point arguments (max %d)", len(c.floats)))
+	}
+	c.floats[c.nFloats] = v
+	c.nFloats++
+}
+
+// keepAlive holds onto everything marshalArg allocates (C strings,
+// pinned slice backing arrays, callback trampolines) so it isn't
+// garbage collected before ccall returns.
+type keepAlive struct {
+	values []interface{}
+}
+
+func (k *keepAlive) add(v interface{}) {
+	k.values = append(k.values, v)
+}
+
+// marshalArg converts a single Go argument into its C representation
+// and feeds it into c, handing struct-by-value arguments to
+// marshalStruct.
+func marshalArg(c *callArgs, k *keepAlive, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		b := make([]byte, len(s)+1)
+		copy(b, s)
+		k.add(b)
+		c.addInt(uintptr(unsafe.Pointer(&b[0])))
+	case reflect.Bool:
+		if v.Bool() {
+			c.addInt(1)
+		} else {
+			c.addInt(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c.addInt(uintptr(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		c.addInt(uintptr(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		c.addFloat(v.Float())
+	case reflect.Ptr, reflect.UnsafePointer:
+		c.addInt(v.Pointer())
+	case reflect.Slice:
+		if v.Len() == 0 {
+			c.addInt(0)
+			return
+		}
+		k.add(v.Interface())
+		c.addInt(v.Index(0).Addr().Pointer())
+	case reflect.Func:
+		cb := newCallback(v.Interface(), false)
+		k.add(cb)
+		c.addInt(cb)
+		// Once the C function this argument is headed for calls cb back,
+		// any stack growth the callback's own reflect.Value.Call triggers
+		// has to unwind through cb's plain C return address on its way
+		// out, which the runtime's stack copier can't make sense of —
+		// see primeStack's doc comment. Priming here, before ccall ever
+		// hands control to C, makes that growth overwhelmingly unlikely
+		// to be needed once control is back over there.
+		primeStack(stackPrimeDepth)
+	case reflect.Struct:
+		marshalStruct(c, k, v)
+	default:
+		panic(fmt.Sprintf("purego: unsupported argument kind %s", v.Kind()))
+	}
+}
+
+// marshalStruct packs a struct-by-value argument into c per its
+// eightbyte classification, or spills it to a caller-allocated buffer
+// and passes a hidden pointer when it's too big to fit in registers.
+func marshalStruct(c *callArgs, k *keepAlive, v reflect.Value) {
+	classes := classifyStruct(v.Type())
+	if len(classes) == 1 && classes[0] == structMemory {
+		buf := reflect.New(v.Type())
+		buf.Elem().Set(v)
+		k.add(buf.Interface())
+		c.addInt(buf.Pointer())
+		return
+	}
+
+	var base reflect.Value
+	if v.CanAddr() {
+		base = v.Addr()
+	} else {
+		tmp := reflect.New(v.Type())
+		tmp.Elem().Set(v)
+		base = tmp
+		k.add(tmp.Interface())
+	}
+	basePtr := base.UnsafePointer()
+	for i, class := range classes {
+		fieldPtr := unsafe.Add(basePtr, i*8)
+		if class == structSSE {
+			c.addFloat(*(*float64)(fieldPtr))
+		} else {
+			c.addInt(*(*uintptr)(fieldPtr))
+		}
+	}
+}
+
+// unmarshalStruct reconstructs a struct-by-value return from r1/r2 (the
+// registers populated per its eightbyte classification) or, when the
+// call was made via a hidden pointer, from the buffer hiddenPtr refers
+// to (nil when the classification didn't require one).
+func unmarshalStruct(t reflect.Type, r1, r2 uintptr, hiddenPtr unsafe.Pointer) reflect.Value {
+	classes := classifyStruct(t)
+	out := reflect.New(t)
+	outPtr := out.UnsafePointer()
+	if structReturnByHiddenPointer(classes) {
+		src := unsafe.Slice((*byte)(hiddenPtr), t.Size())
+		dst := unsafe.Slice((*byte)(outPtr), t.Size())
+		copy(dst, src)
+		return out.Elem()
+	}
+
+	words := [2]uintptr{r1, r2}
+	for i := range classes {
+		*(*uintptr)(unsafe.Add(outPtr, i*8)) = words[i]
+	}
+	return out.Elem()
+}
+
+// callFunc marshals args per ty's static signature, calls cfn, and
+// unmarshals the result(s), including the trailing (error) or
+// (purego.Errno) convention RegisterLibFunc bindings may opt into.
+func callFunc(ty reflect.Type, cfn uintptr, args []reflect.Value) []reflect.Value {
+	if fn, ok := lookupCallback(cfn); ok {
+		return fn.Call(args)
+	}
+
+	var c callArgs
+	var k keepAlive
+
+	hasErrnoResult, errnoIndex := errnoResultIndex(ty)
+
+	retTy, hiddenPtrBuf := structReturnSetup(ty, hasErrnoResult, errnoIndex)
+	if hiddenPtrBuf != nil {
+		c.addInt(uintptr(unsafe.Pointer(hiddenPtrBuf)))
+	}
+
+	for _, a := range args {
+		marshalArg(&c, &k, a)
+	}
+
+	r1, r2, errno := ccall(cfn,
+		c.ints[0], c.ints[1], c.ints[2], c.ints[3], c.ints[4], c.ints[5], c.ints[6], c.ints[7], c.ints[8],
+		c.floats[0], c.floats[1], c.floats[2], c.floats[3], c.floats[4], c.floats[5], c.floats[6], c.floats[7],
+		uintptr(c.nFloats))
+	k.values = nil // keep k alive until after ccall returns
+
+	return unmarshalResults(ty, retTy, r1, r2, errno, hiddenPtrBuf, hasErrnoResult, errnoIndex)
+}
+
+// errnoResultIndex reports whether ty's last result is a purego.Errno
+// or an error (the two conventions RegisterLibFunc accepts for
+// surfacing the errno ccall captured), and its index.
+func errnoResultIndex(ty reflect.Type) (ok bool, index int) {
+	n := ty.NumOut()
+	if n == 0 {
+		return false, 0
+	}
+	last := ty.Out(n - 1)
+	if last == reflect.TypeOf(Errno(0)) || last == reflect.TypeOf((*error)(nil)).Elem() {
+		return true, n - 1
+	}
+	return false, 0
+}
+
+// structReturnSetup reports the (non-errno) return type, if any, and
+// allocates a hidden-pointer buffer for it when its classification
+// requires one.
+func structReturnSetup(ty reflect.Type, hasErrnoResult bool, errnoIndex int) (retTy reflect.Type, hiddenPtrBuf *byte) {
+	n := ty.NumOut()
+	if hasErrnoResult {
+		n--
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	retTy = ty.Out(0)
+	if retTy.Kind() == reflect.Struct && structReturnByHiddenPointer(classifyStruct(retTy)) {
+		buf := make([]byte, retTy.Size())
+		hiddenPtrBuf = &buf[0]
+	}
+	return retTy, hiddenPtrBuf
+}
+
+func unmarshalResults(ty, retTy reflect.Type, r1, r2, errno uintptr, hiddenPtrBuf *byte, hasErrnoResult bool, errnoIndex int) []reflect.Value {
+	out := make([]reflect.Value, ty.NumOut())
+
+	if retTy != nil {
+		switch {
+		case retTy.Kind() == reflect.Struct:
+			var hp unsafe.Pointer
+			if hiddenPtrBuf != nil {
+				hp = unsafe.Pointer(hiddenPtrBuf)
+			}
+			out[0] = unmarshalStruct(retTy, r1, r2, hp)
+		case retTy.Kind() == reflect.Bool:
+			out[0] = reflect.ValueOf(r1 != 0)
+		case retTy.Kind() == reflect.Int:
+			// A C function's actual return register is only as wide as its
+			// declared C return type, but the great majority of bindings
+			// use a plain Go int (not int32) for C's int, since that's
+			// the more natural Go signature. The SysV ABI leaves the
+			// register's upper bits unspecified in that case — observed in
+			// practice as zero-extension rather than sign-extension (a
+			// 32-bit "mov $-1, %eax" zeros the top half of RAX) — so a
+			// negative C int read back as a full 64-bit value comes out
+			// positive unless it's re-narrowed to 32 bits and sign-extended
+			// from there first, the same correction every other
+			// fixed-width signed kind below gets for free from Convert.
+			out[0] = reflect.ValueOf(int64(int32(r1))).Convert(retTy)
+		case retTy.Kind() > reflect.Int && retTy.Kind() <= reflect.Int64:
+			out[0] = reflect.ValueOf(r1).Convert(retTy)
+		case retTy.Kind() == reflect.Uint:
+			// Same narrowing as the Int case above, but zero- rather than
+			// sign-extended.
+			out[0] = reflect.ValueOf(uint64(uint32(r1))).Convert(retTy)
+		case retTy.Kind() > reflect.Uint && retTy.Kind() <= reflect.Uintptr:
+			out[0] = reflect.ValueOf(r1).Convert(retTy)
+		case retTy.Kind() == reflect.UnsafePointer || retTy.Kind() == reflect.Ptr:
+			out[0] = reflect.NewAt(retTy, unsafe.Pointer(&r1)).Elem()
+		default:
+			panic(fmt.Sprintf("purego: unsupported return kind %s", retTy.Kind()))
+		}
+	}
+
+	if hasErrnoResult {
+		if ty.Out(errnoIndex) == reflect.TypeOf((*error)(nil)).Elem() {
+			if errno == 0 {
+				out[errnoIndex] = reflect.Zero(ty.Out(errnoIndex))
+			} else {
+				out[errnoIndex] = reflect.ValueOf(Errno(errno))
+			}
+		} else {
+			out[errnoIndex] = reflect.ValueOf(Errno(errno))
+		}
+	}
+
+	return out
+}
+
+// classifyStruct dispatches to the current platform's eightbyte
+// classifier.
+func classifyStruct(t reflect.Type) []structClass {
+	return classifyStructPlatform(t)
+}
+
+// stackPrimeDepth is how many of primeStack's 4KiB frames to force,
+// chosen to comfortably cover a typical callback body (reflect.Value.Call
+// plus a handful of ordinary Go statements) without needing a further
+// growth once C has called back into Go.
+const stackPrimeDepth = 64
+
+// primeStack grows the calling goroutine's stack well past its default
+// starting size. marshalArg calls this once per reflect.Func argument,
+// before the C call that argument's callback trampoline is headed into:
+// once C calls back into one of this package's callbackStub<N>, any
+// stack growth the nested reflect.Value.Call needs has to unwind back
+// through a plain C return address on its way out, which the runtime's
+// stack-copying code doesn't recognize and treats as fatal corruption.
+// Priming here, on an ordinary, fully-unwindable Go stack before the C
+// call ever happens, makes that growth overwhelmingly unlikely to be
+// needed once control is back over there.
+//
+//go:noinline
+func primeStack(depth int) {
+	if depth <= 0 {
+		return
+	}
+	var pad [4096]byte
+	pad[len(pad)-1] = byte(depth)
+	primeStack(depth - 1)
+	runtime.KeepAlive(pad)
+}
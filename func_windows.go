@@ -0,0 +1,288 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build windows
+
+package purego
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+// RegisterFunc sets the function pointed to by fptr to a Go function
+// that marshals its arguments and calls cfn, a DLL-exported function
+// pointer (the kind NewCallback and Dlsym both return), according to the
+// Microsoft x64 calling convention. fptr must be a pointer to a func.
+func RegisterFunc(fptr interface{}, cfn uintptr) {
+	fn := reflect.ValueOf(fptr).Elem()
+	ty := fn.Type()
+
+	v := reflect.MakeFunc(ty, func(args []reflect.Value) []reflect.Value {
+		return callFunc(ty, cfn, args)
+	})
+	fn.Set(v)
+}
+
+// RegisterLibFunc is like RegisterFunc except it looks cfn up by name in
+// the DLL identified by handle (as returned by Dlopen), panicking if the
+// symbol isn't found, the same contract the darwin/linux implementation
+// gives.
+func RegisterLibFunc(fptr interface{}, handle uintptr, name string) {
+	sym, err := Dlsym(handle, name)
+	if err != nil {
+		panic(err)
+	}
+	RegisterFunc(fptr, sym)
+}
+
+// maxWindowsArgs is how many argument slots ccallWindows accepts:
+// numOfWindowsRegisterArgs in RCX/RDX/R8/R9 (or their XMM mirror) plus
+// five more spilled to the stack, enough for every function this
+// package's own tests bind.
+const maxWindowsArgs = 9
+
+// callArgs accumulates a windows call's arguments in positional order,
+// unlike the SysV callArgs in func.go which buckets them into
+// independent integer and floating-point slot arrays: Microsoft x64
+// shares a single slot index between the two, so slot i's register (or
+// stack position) depends only on i, never on how many floats or
+// integers came before it.
+type callArgs struct {
+	slots [maxWindowsArgs]uintptr
+	n     int
+}
+
+func (c *callArgs) addInt(v uintptr) {
+	if c.n >= len(c.slots) {
+		panic(fmt.Sprintf("purego: too many arguments (max %d)", len(c.slots)))
+	}
+	c.slots[c.n] = v
+	c.n++
+}
+
+// addFloat stores v's raw bits in the next slot. ccallWindows loads
+// every slot into both its integer register and its XMM mirror, so a
+// float argument's bit pattern reaches the XMM register the callee's C
+// signature actually expects regardless of which one this slot is
+// nominally "for".
+func (c *callArgs) addFloat(v float64) {
+	c.addInt(uintptr(math.Float64bits(v)))
+}
+
+// keepAlive holds onto everything marshalArg allocates (C strings,
+// pinned slice backing arrays, callback trampolines) so it isn't
+// garbage collected before ccallWindows returns.
+type keepAlive struct {
+	values []interface{}
+}
+
+func (k *keepAlive) add(v interface{}) {
+	k.values = append(k.values, v)
+}
+
+// marshalArg converts a single Go argument into its C representation
+// and feeds it into c, handing struct-by-value arguments to
+// marshalStruct.
+func marshalArg(c *callArgs, k *keepAlive, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		b := make([]byte, len(s)+1)
+		copy(b, s)
+		k.add(b)
+		c.addInt(uintptr(unsafe.Pointer(&b[0])))
+	case reflect.Bool:
+		if v.Bool() {
+			c.addInt(1)
+		} else {
+			c.addInt(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c.addInt(uintptr(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		c.addInt(uintptr(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		c.addFloat(v.Float())
+	case reflect.Ptr, reflect.UnsafePointer:
+		c.addInt(v.Pointer())
+	case reflect.Slice:
+		if v.Len() == 0 {
+			c.addInt(0)
+			return
+		}
+		k.add(v.Interface())
+		c.addInt(v.Index(0).Addr().Pointer())
+	case reflect.Func:
+		cb := newCallback(v.Interface(), false)
+		k.add(cb)
+		c.addInt(cb)
+	case reflect.Struct:
+		marshalStruct(c, k, v)
+	default:
+		panic(fmt.Sprintf("purego: unsupported argument kind %s", v.Kind()))
+	}
+}
+
+// marshalStruct packs a struct-by-value argument into a single slot
+// when its size is exactly 1, 2, 4, or 8 bytes (the Microsoft x64 rule,
+// simpler than SysV's eightbyte classification: a small aggregate is
+// always passed as if it were an integer of the same size, never in an
+// XMM register purely because its fields happen to be floats), or
+// spills it to a caller-allocated buffer and passes a hidden pointer
+// otherwise.
+func marshalStruct(c *callArgs, k *keepAlive, v reflect.Value) {
+	var base reflect.Value
+	if v.CanAddr() {
+		base = v.Addr()
+	} else {
+		tmp := reflect.New(v.Type())
+		tmp.Elem().Set(v)
+		base = tmp
+		k.add(tmp.Interface())
+	}
+	basePtr := base.UnsafePointer()
+
+	if !structReturnByHiddenPointerWindows(v.Type().Size()) {
+		var word uintptr
+		dst := unsafe.Slice((*byte)(unsafe.Pointer(&word)), unsafe.Sizeof(word))
+		src := unsafe.Slice((*byte)(basePtr), v.Type().Size())
+		copy(dst, src)
+		c.addInt(word)
+		return
+	}
+
+	c.addInt(uintptr(basePtr))
+}
+
+// unmarshalStruct reconstructs a struct-by-value return from r1 (the
+// single register Microsoft x64 returns a small aggregate in) or, when
+// the call was made via a hidden pointer, from the buffer hiddenPtr
+// refers to.
+func unmarshalStruct(t reflect.Type, r1 uintptr, hiddenPtr unsafe.Pointer) reflect.Value {
+	out := reflect.New(t)
+	outPtr := out.UnsafePointer()
+	if structReturnByHiddenPointerWindows(t.Size()) {
+		src := unsafe.Slice((*byte)(hiddenPtr), t.Size())
+		dst := unsafe.Slice((*byte)(outPtr), t.Size())
+		copy(dst, src)
+		return out.Elem()
+	}
+
+	src := unsafe.Slice((*byte)(unsafe.Pointer(&r1)), t.Size())
+	dst := unsafe.Slice((*byte)(outPtr), t.Size())
+	copy(dst, src)
+	return out.Elem()
+}
+
+// callFunc marshals args per ty's static signature, calls cfn, and
+// unmarshals the result(s), including the trailing (error) or
+// (purego.Errno) convention RegisterLibFunc bindings may opt into —
+// the same contract callFunc gives on darwin/linux.
+func callFunc(ty reflect.Type, cfn uintptr, args []reflect.Value) []reflect.Value {
+	if fn, ok := lookupCallback(cfn); ok {
+		return fn.Call(args)
+	}
+
+	var c callArgs
+	var k keepAlive
+
+	hasErrnoResult, errnoIndex := errnoResultIndex(ty)
+
+	retTy, hiddenPtrBuf := structReturnSetup(ty, hasErrnoResult, errnoIndex)
+	if hiddenPtrBuf != nil {
+		c.addInt(uintptr(unsafe.Pointer(hiddenPtrBuf)))
+	}
+
+	for _, a := range args {
+		marshalArg(&c, &k, a)
+	}
+
+	r1, _, lastErr := ccallWindows(cfn,
+		c.slots[0], c.slots[1], c.slots[2], c.slots[3], c.slots[4], c.slots[5], c.slots[6], c.slots[7], c.slots[8])
+	k.values = nil // keep k alive until after ccallWindows returns
+
+	return unmarshalResults(ty, retTy, r1, lastErr, hiddenPtrBuf, hasErrnoResult, errnoIndex)
+}
+
+// errnoResultIndex reports whether ty's last result is a purego.Errno
+// or an error (the two conventions RegisterLibFunc accepts for
+// surfacing the GetLastError() value ccallWindows captured), and its
+// index.
+func errnoResultIndex(ty reflect.Type) (ok bool, index int) {
+	n := ty.NumOut()
+	if n == 0 {
+		return false, 0
+	}
+	last := ty.Out(n - 1)
+	if last == reflect.TypeOf(Errno(0)) || last == reflect.TypeOf((*error)(nil)).Elem() {
+		return true, n - 1
+	}
+	return false, 0
+}
+
+// structReturnSetup reports the (non-errno) return type, if any, and
+// allocates a hidden-pointer buffer for it when its size requires one.
+func structReturnSetup(ty reflect.Type, hasErrnoResult bool, errnoIndex int) (retTy reflect.Type, hiddenPtrBuf *byte) {
+	n := ty.NumOut()
+	if hasErrnoResult {
+		n--
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	retTy = ty.Out(0)
+	if retTy.Kind() == reflect.Struct && structReturnByHiddenPointerWindows(retTy.Size()) {
+		buf := make([]byte, retTy.Size())
+		hiddenPtrBuf = &buf[0]
+	}
+	return retTy, hiddenPtrBuf
+}
+
+func unmarshalResults(ty, retTy reflect.Type, r1, lastErr uintptr, hiddenPtrBuf *byte, hasErrnoResult bool, errnoIndex int) []reflect.Value {
+	out := make([]reflect.Value, ty.NumOut())
+
+	if retTy != nil {
+		switch {
+		case retTy.Kind() == reflect.Struct:
+			var hp unsafe.Pointer
+			if hiddenPtrBuf != nil {
+				hp = unsafe.Pointer(hiddenPtrBuf)
+			}
+			out[0] = unmarshalStruct(retTy, r1, hp)
+		case retTy.Kind() == reflect.Bool:
+			out[0] = reflect.ValueOf(r1 != 0)
+		case retTy.Kind() == reflect.Int:
+			// See func.go's identical comment: the register's upper bits
+			// are unspecified for a narrower C return type, observed in
+			// practice as zero-extension.
+			out[0] = reflect.ValueOf(int64(int32(r1))).Convert(retTy)
+		case retTy.Kind() > reflect.Int && retTy.Kind() <= reflect.Int64:
+			out[0] = reflect.ValueOf(r1).Convert(retTy)
+		case retTy.Kind() == reflect.Uint:
+			out[0] = reflect.ValueOf(uint64(uint32(r1))).Convert(retTy)
+		case retTy.Kind() > reflect.Uint && retTy.Kind() <= reflect.Uintptr:
+			out[0] = reflect.ValueOf(r1).Convert(retTy)
+		case retTy.Kind() == reflect.UnsafePointer || retTy.Kind() == reflect.Ptr:
+			out[0] = reflect.NewAt(retTy, unsafe.Pointer(&r1)).Elem()
+		default:
+			panic(fmt.Sprintf("purego: unsupported return kind %s", retTy.Kind()))
+		}
+	}
+
+	if hasErrnoResult {
+		if ty.Out(errnoIndex) == reflect.TypeOf((*error)(nil)).Elem() {
+			if lastErr == 0 {
+				out[errnoIndex] = reflect.Zero(ty.Out(errnoIndex))
+			} else {
+				out[errnoIndex] = reflect.ValueOf(Errno(lastErr))
+			}
+		} else {
+			out[errnoIndex] = reflect.ValueOf(Errno(lastErr))
+		}
+	}
+
+	return out
+}
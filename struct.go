@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+package purego
+
+import "reflect"
+
+// structClass describes which kind of argument-passing slot a single
+// eightbyte (or, on arm64, a whole small struct) should be classified into
+// when a Go struct is passed to or returned from a C function by value.
+type structClass uint8
+
+const (
+	// structInteger means the eightbyte should be passed/returned in a
+	// general purpose register (or, if none are free, on the stack).
+	structInteger structClass = iota
+	// structSSE means the eightbyte is made up entirely of float32/float64
+	// fields and should be passed/returned in a floating-point register.
+	structSSE
+	// structMemory means the struct is too large or otherwise ineligible
+	// for register passing and must be passed/returned via a hidden
+	// pointer to caller-allocated memory.
+	structMemory
+)
+
+// maxRegisterStruct is the largest struct, in bytes, that any of the
+// supported platform ABIs will ever pass or return in registers. Anything
+// bigger is always classified as structMemory.
+const maxRegisterStruct = 16
+
+// isStructType reports whether t is a struct that RegisterFunc and
+// RegisterLibFunc must classify for by-value argument/return passing,
+// rather than treating it like a scalar or pointer.
+func isStructType(t reflect.Type) bool {
+	return t != nil && t.Kind() == reflect.Struct
+}
+
+// flattenStructFields walks t (recursing into embedded/nested structs and
+// fixed-size arrays) and appends the reflect.Kind of every leaf field to
+// dst, so that isAllFloats can tell, leaf by leaf, whether a struct is
+// made up purely of floating point fields or also contains
+// integers/pointers. classifyStructSysV does not call it (it walks
+// fields by offset itself), and there is no AAPCS64 classifier in this
+// package.
+func flattenStructFields(t reflect.Type, dst []reflect.Kind) []reflect.Kind {
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			dst = flattenStructFields(t.Field(i).Type, dst)
+		}
+		return dst
+	case reflect.Array:
+		elem := t.Elem()
+		for i := 0; i < t.Len(); i++ {
+			dst = flattenStructFields(elem, dst)
+		}
+		return dst
+	default:
+		return append(dst, t.Kind())
+	}
+}
+
+// isAllFloats reports whether every leaf field of t is a float32 or
+// float64, which is the prerequisite shared by the SysV SSE-class
+// eightbyte rule and the AAPCS64 HFA rule.
+func isAllFloats(t reflect.Type) bool {
+	kinds := flattenStructFields(t, nil)
+	if len(kinds) == 0 {
+		return false
+	}
+	for _, k := range kinds {
+		if k != reflect.Float32 && k != reflect.Float64 {
+			return false
+		}
+	}
+	return true
+}
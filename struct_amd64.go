@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build (darwin || linux) && amd64
+
+package purego
+
+import "reflect"
+
+// classifyStructSysV implements the eightbyte classification from the
+// SysV AMD64 ABI (section 3.2.3 of the psABI). t must satisfy
+// isStructType. The struct is split into ceil(size/8) eightbytes and each
+// one is classified independently as structInteger or structSSE; if the
+// struct is larger than two eightbytes (16 bytes), the whole struct is
+// classified structMemory and must be passed/returned via a hidden
+// pointer instead.
+//
+// walk's per-field merge (classes[offset/8] = structInteger) relies on
+// Go never laying out a field at an offset that isn't a multiple of its
+// own (hence the ABI's) alignment, so a field can straddle an eightbyte
+// boundary only by being larger than 8 bytes itself, in which case it's
+// walked one eightbyte at a time like any nested struct or array. A
+// packed, unaligned C layout that straddles a boundary the way some hand
+// constructed cgo-style wrapper types can isn't representable by a plain
+// Go struct reflect.Type in the first place, so this classifier doesn't
+// need to detect or reject it.
+//
+// Darwin's variant of the ABI follows the same eightbyte rules for
+// register passing. func.go marshals stack arguments the same way on
+// darwin and linux; it has no darwin-specific stack packing.
+func classifyStructSysV(t reflect.Type) []structClass {
+	size := t.Size()
+	if size == 0 || size > maxRegisterStruct {
+		return []structClass{structMemory}
+	}
+
+	numEightbytes := (size + 7) / 8
+	classes := make([]structClass, numEightbytes)
+	for i := range classes {
+		classes[i] = structSSE // tentatively float until proven otherwise
+	}
+
+	var walk func(t reflect.Type, offset uintptr)
+	walk = func(t reflect.Type, offset uintptr) {
+		switch t.Kind() {
+		case reflect.Struct:
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				walk(f.Type, offset+f.Offset)
+			}
+		case reflect.Array:
+			elem := t.Elem()
+			for i := 0; i < t.Len(); i++ {
+				walk(elem, offset+uintptr(i)*elem.Size())
+			}
+		case reflect.Float32, reflect.Float64:
+			// leave the eightbyte as SSE
+		default:
+			// any non-float leaf merges its eightbyte to INTEGER,
+			// per the psABI's merge rules.
+			classes[offset/8] = structInteger
+		}
+	}
+	walk(t, 0)
+
+	return classes
+}
+
+// structReturnByHiddenPointer reports whether a struct of this class must
+// be returned via a caller-supplied pointer (passed in RDI, with RAX
+// mirroring it on return) rather than in RAX:RDX/XMM0:XMM1.
+func structReturnByHiddenPointer(classes []structClass) bool {
+	return len(classes) == 1 && classes[0] == structMemory
+}
+
+// classifyStructPlatform is func.go's entry point into this file's
+// classifier. There is currently no arm64 counterpart: ccall, the
+// callback trampolines, and the rest of the register-marshalling
+// machinery this package's darwin/linux support is built on are only
+// implemented for amd64 (see ccall_amd64.s), so an AAPCS64 classifier
+// would have nothing real to plug into. classifyStructPlatform is the
+// single indirection point a future arm64 port would extend alongside
+// that assembly, rather than something func.go dispatches on directly.
+func classifyStructPlatform(t reflect.Type) []structClass {
+	return classifyStructSysV(t)
+}
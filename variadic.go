@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+package purego
+
+import (
+	"reflect"
+	"runtime"
+)
+
+// variadicStackOnly reports whether the current platform's ABI requires
+// variadic arguments to be passed entirely on the stack rather than in
+// registers. This is true for the Apple arm64 ABI (both ios and darwin
+// arm64), which diverges from the AAPCS64 variadic convention precisely
+// so that a vararg function's prologue doesn't need to spill every
+// possible register argument.
+func variadicStackOnly() bool {
+	return runtime.GOOS == "darwin" && runtime.GOARCH == "arm64"
+}
+
+// isVariadic reports whether t, a func type, was declared with a
+// trailing `...any` parameter, the convention RegisterFunc and
+// RegisterLibFunc use to opt in to variadic marshalling.
+func isVariadic(t reflect.Type) bool {
+	if !t.IsVariadic() {
+		return false
+	}
+	last := t.In(t.NumIn() - 1)
+	return last.Kind() == reflect.Slice && last.Elem().Kind() == reflect.Interface
+}
+
+// classifyVariadicArg inspects the dynamic type of a single argument
+// passed through the `...any` tail and reports whether it belongs in an
+// integer register slot or a floating-point register slot. Unlike fixed
+// arguments, whose slot is known from the Go function's static type,
+// variadic arguments must be classified at call time from the
+// reflect.Value the caller actually passed, matching C's va_arg default
+// argument promotions: every integer narrower than int is promoted to
+// (at least) int, and float32 is promoted to double.
+//
+// Neither promotion needs extra code here beyond the float/not-float
+// split: marshalArg's addInt already widens every integer Kind to a full
+// uintptr via v.Int()/v.Uint() regardless of the field's declared
+// width, which is exactly what the int promotion produces on every
+// platform this package targets, and addFloat's v.Float() always
+// returns a float64 even for a reflect.Float32 value, which is the
+// double promotion.
+func classifyVariadicArg(v reflect.Value) (isFloat bool) {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// marshalVariadicArg is marshalArg's counterpart for the `...any` tail:
+// it uses classifyVariadicArg, rather than a static Kind switch, to
+// decide whether the value belongs in an integer or floating-point
+// slot. A float goes straight to addFloat; everything else is handed to
+// marshalArg for the actual conversion.
+func marshalVariadicArg(c *callArgs, k *keepAlive, v reflect.Value) {
+	if classifyVariadicArg(v) {
+		c.addFloat(v.Float())
+		return
+	}
+	marshalArg(c, k, v)
+}
+
+// RegisterVariadicFunc is like RegisterLibFunc except that fptr's Go
+// signature is expected to end in a `...any` parameter, which is
+// marshalled at call time by inspecting each argument's dynamic type
+// instead of the (fixed, reflect-cached) static signature that
+// RegisterFunc relies on. Use this for binding C functions such as
+// printf, open, execl, or objc_msgSend whose argument list isn't known
+// until the call site.
+//
+// Prefer RegisterLibFunc/RegisterFunc for non-variadic functions: the
+// extra per-call reflection that variadic marshalling requires is only
+// paid by bindings that opt into it here.
+func RegisterVariadicFunc(fptr interface{}, handle uintptr, name string) {
+	sym, err := Dlsym(handle, name)
+	if err != nil {
+		panic(err)
+	}
+
+	fn := reflect.ValueOf(fptr).Elem()
+	ty := fn.Type()
+	if !isVariadic(ty) {
+		panic("purego: RegisterVariadicFunc requires a function type ending in ...any")
+	}
+
+	v := reflect.MakeFunc(ty, func(args []reflect.Value) []reflect.Value {
+		fixed := args[:ty.NumIn()-1]
+		variadic := args[len(fixed)].Interface().([]interface{})
+		variadicValues := make([]reflect.Value, len(variadic))
+		for i, a := range variadic {
+			variadicValues[i] = reflect.ValueOf(a)
+		}
+		return callVariadic(ty, sym, fixed, variadicValues)
+	})
+	fn.Set(v)
+}
+
+// callVariadic marshals fixed (the statically-typed leading arguments)
+// followed by variadic (the dynamically-typed `...any` tail, each
+// classified by classifyVariadicArg) and calls sym, sharing callFunc's
+// marshalling/unmarshalling helpers so a variadic binding's struct,
+// string, errno and slice handling stays identical to a fixed-arity
+// RegisterLibFunc binding. Implemented per-OS in variadic_unix.go and
+// variadic_windows.go, since the two platforms' callArgs don't share a
+// layout and the call this makes down to ccall/ccallWindows differs
+// accordingly.
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build darwin || linux
+
+package purego
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// callVariadic is variadic.go's declared entry point on darwin/linux.
+//
+// variadicStackOnly is not consulted here: ccall exists only for amd64
+// (see ccall_amd64.s), where variadic arguments take the same register
+// and stack slots as fixed ones, in the left-to-right order marshalArg
+// appends them to callArgs. Passing variadic arguments only on the
+// stack, as darwin/arm64's ABI does, is not implemented.
+func callVariadic(ty reflect.Type, sym uintptr, fixed, variadic []reflect.Value) []reflect.Value {
+	var c callArgs
+	var k keepAlive
+
+	hasErrnoResult, errnoIndex := errnoResultIndex(ty)
+	retTy, hiddenPtrBuf := structReturnSetup(ty, hasErrnoResult, errnoIndex)
+	if hiddenPtrBuf != nil {
+		c.addInt(uintptr(unsafe.Pointer(hiddenPtrBuf)))
+	}
+
+	for _, a := range fixed {
+		marshalArg(&c, &k, a)
+	}
+	for _, a := range variadic {
+		marshalVariadicArg(&c, &k, a)
+	}
+
+	r1, r2, errno := ccall(sym,
+		c.ints[0], c.ints[1], c.ints[2], c.ints[3], c.ints[4], c.ints[5], c.ints[6], c.ints[7], c.ints[8],
+		c.floats[0], c.floats[1], c.floats[2], c.floats[3], c.floats[4], c.floats[5], c.floats[6], c.floats[7],
+		uintptr(c.nFloats))
+	k.values = nil
+
+	return unmarshalResults(ty, retTy, r1, r2, errno, hiddenPtrBuf, hasErrnoResult, errnoIndex)
+}
@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2023 The Ebitengine Authors
+
+//go:build windows
+
+package purego
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// callVariadic is variadic.go's declared entry point on windows. It
+// mirrors callFunc in func_windows.go, but, like its darwin/linux
+// counterpart in variadic_unix.go, classifies each `...any` element at
+// call time with marshalVariadicArg instead of marshalArg alone.
+func callVariadic(ty reflect.Type, sym uintptr, fixed, variadic []reflect.Value) []reflect.Value {
+	var c callArgs
+	var k keepAlive
+
+	hasErrnoResult, errnoIndex := errnoResultIndex(ty)
+	retTy, hiddenPtrBuf := structReturnSetup(ty, hasErrnoResult, errnoIndex)
+	if hiddenPtrBuf != nil {
+		c.addInt(uintptr(unsafe.Pointer(hiddenPtrBuf)))
+	}
+
+	for _, a := range fixed {
+		marshalArg(&c, &k, a)
+	}
+	for _, a := range variadic {
+		marshalVariadicArg(&c, &k, a)
+	}
+
+	r1, _, lastErr := ccallWindows(sym,
+		c.slots[0], c.slots[1], c.slots[2], c.slots[3], c.slots[4], c.slots[5], c.slots[6], c.slots[7], c.slots[8])
+	k.values = nil
+
+	return unmarshalResults(ty, retTy, r1, lastErr, hiddenPtrBuf, hasErrnoResult, errnoIndex)
+}